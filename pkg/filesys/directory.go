@@ -1,6 +1,7 @@
 package filesys
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,9 +9,19 @@ import (
 	"github.com/otiai10/copy"
 )
 
-// Copy directory from src to dst
-// remove dst repository if it exists
+// ReplaceWithCopy is ReplaceWithCopyContext with context.Background().
 func ReplaceWithCopy(src, dst string) error {
+	return ReplaceWithCopyContext(context.Background(), src, dst)
+}
+
+// ReplaceWithCopyContext copies directory src over dst, removing dst first if it exists. ctx is
+// checked before the underlying copy.Copy call, which does not itself support cancellation, so a
+// caller that cancels ctx while queued behind other I/O still skips the copy.
+func ReplaceWithCopyContext(ctx context.Context, src, dst string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if _, err := os.Stat(dst); err == nil {
 		if err = os.RemoveAll(dst); err != nil {
 			return fmt.Errorf("remove existing bundle: %w", err)
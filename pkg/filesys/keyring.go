@@ -0,0 +1,83 @@
+package filesys
+
+import (
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Keyring is a set of trusted RSA public keys indexed by key id.
+type Keyring map[string]*rsa.PublicKey
+
+// GetKeysDir returns the directory trusted public keys are loaded from, creating it if
+// necessary (~/.cti/keys).
+func GetKeysDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get user home dir: %w", err)
+	}
+	dir := filepath.Join(home, ".cti", "keys")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", fmt.Errorf("create keys dir %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// LoadKeyring reads every ".pub" PEM file in dir into a Keyring, keyed by the id KeyID derives
+// from each key; the file name itself is not used as the key id.
+func LoadKeyring(dir string) (Keyring, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read keys dir %s: %w", dir, err)
+	}
+
+	keyring := make(Keyring)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pub") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read key %s: %w", entry.Name(), err)
+		}
+
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("decode PEM block in %s", entry.Name())
+		}
+
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key %s: %w", entry.Name(), err)
+		}
+		rsaPub, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("key %s is not an RSA public key", entry.Name())
+		}
+
+		keyID, err := KeyID(rsaPub)
+		if err != nil {
+			return nil, fmt.Errorf("derive key id for %s: %w", entry.Name(), err)
+		}
+		keyring[keyID] = rsaPub
+	}
+	return keyring, nil
+}
+
+// KeyID derives a key id from the SHA-1 digest of the key's DER-encoded SubjectPublicKeyInfo,
+// mirroring the approach used by Alpine's APKINDEX signing.
+func KeyID(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("marshal public key: %w", err)
+	}
+	sum := sha1.Sum(der)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,287 @@
+package pacman
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_package "github.com/acronis/go-cti/pkg/package"
+	"github.com/acronis/go-cti/pkg/parser"
+	"github.com/akrylysov/pogreb"
+)
+
+// CacheMode selects how Validate loads dependency metadata.
+type CacheMode string
+
+const (
+	// CacheModePogreb keeps a persistent, mmap-backed key-value store under PackageCacheDir.
+	// It is the default: repeat Validate calls skip re-reading every dependency from disk.
+	CacheModePogreb CacheMode = "pogreb"
+	// CacheModeJSON re-reads parser.MetadataCacheFile from each dependency's directory on
+	// every call, as before. Kept for environments that cannot use mmap.
+	CacheModeJSON CacheMode = "json"
+	// CacheModeNone disables caching entirely: every Validate call reparses dependencies
+	// from source.
+	CacheModeNone CacheMode = "none"
+)
+
+const (
+	metadataCacheDBName = "metadata.pogreb"
+	cacheBatchSize      = 200
+)
+
+// Option configures a PackageManager at construction time.
+type Option func(*PackageManager)
+
+// WithCacheMode selects how dependency metadata is cached between Validate calls.
+func WithCacheMode(mode CacheMode) Option {
+	return func(pacman *PackageManager) {
+		pacman.cacheMode = mode
+	}
+}
+
+// metadataCache is a thin wrapper around the pogreb store, keyed by
+// "<appCode>@<lockedVersion>/<cti-id>", with an additional "<appCode>@<lockedVersion>/__index__"
+// key per bundle version holding the list of CTI ids it contains.
+type metadataCache struct {
+	db *pogreb.DB
+}
+
+func openMetadataCache(cacheDir string) (*metadataCache, error) {
+	db, err := pogreb.Open(filepath.Join(cacheDir, metadataCacheDBName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("open metadata cache: %w", err)
+	}
+	return &metadataCache{db: db}, nil
+}
+
+func (c *metadataCache) Close() error {
+	return c.db.Close()
+}
+
+func cacheEntityKey(appCode, version, ctiID string) []byte {
+	return []byte(fmt.Sprintf("%s@%s/%s", appCode, version, ctiID))
+}
+
+func cacheIndexKey(appCode, version string) []byte {
+	return []byte(fmt.Sprintf("%s@%s/__index__", appCode, version))
+}
+
+// buildMetadataCacheForDependency streams parser.MetadataCacheFile for a dependency into the
+// cache in batches, decoding one entity at a time with a json.Decoder so the whole registry is
+// never held in memory at once. It builds that file via parser.BuildPackageCache first if a
+// prior install hasn't already produced it.
+func (pacman *PackageManager) buildMetadataCacheForDependency(cache *metadataCache, appCode string, idx *_package.Index) error {
+	metadataPath := filepath.Join(idx.BaseDir, parser.MetadataCacheFile)
+	if _, err := os.Stat(metadataPath); os.IsNotExist(err) {
+		if err := parser.BuildPackageCache(idx.FilePath); err != nil {
+			return fmt.Errorf("build metadata cache for %s: %w", appCode, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("stat metadata cache for %s: %w", appCode, err)
+	}
+
+	f, err := os.Open(metadataPath)
+	if err != nil {
+		return fmt.Errorf("open metadata cache for %s: %w", appCode, err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	if _, err := dec.Token(); err != nil { // consume the opening '{' of the top-level object
+		return fmt.Errorf("decode metadata cache for %s: %w", appCode, err)
+	}
+
+	batch := cache.db.NewBatch()
+	var ids []string
+	pending := 0
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("decode metadata cache for %s: %w", appCode, err)
+		}
+		id, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("decode metadata cache for %s: unexpected key %v", appCode, tok)
+		}
+
+		var entity json.RawMessage
+		if err := dec.Decode(&entity); err != nil {
+			return fmt.Errorf("decode entity %s for %s: %w", id, appCode, err)
+		}
+
+		if err := batch.Put(cacheEntityKey(appCode, idx.Version, id), entity); err != nil {
+			return fmt.Errorf("put entity %s: %w", id, err)
+		}
+		ids = append(ids, id)
+		pending++
+		if pending == cacheBatchSize {
+			if err := batch.Write(); err != nil {
+				return fmt.Errorf("write cache batch: %w", err)
+			}
+			batch = cache.db.NewBatch()
+			pending = 0
+		}
+	}
+	if pending > 0 {
+		if err := batch.Write(); err != nil {
+			return fmt.Errorf("write cache batch: %w", err)
+		}
+	}
+
+	indexData, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("marshal cache index for %s: %w", appCode, err)
+	}
+	if err := cache.db.Put(cacheIndexKey(appCode, idx.Version), indexData); err != nil {
+		return fmt.Errorf("put cache index for %s: %w", appCode, err)
+	}
+	return nil
+}
+
+// loadCachedEntities returns the cached entities for a locked dependency, keyed by CTI id,
+// building the cache for it first if it isn't present yet.
+func (pacman *PackageManager) loadCachedEntities(cache *metadataCache, dep _package.PackageLock) (map[string]json.RawMessage, error) {
+	idx, err := _package.ReadIndexFile(filepath.Join(pacman.DependenciesDir, dep.AppCode, _package.IndexFileName))
+	if err != nil {
+		return nil, fmt.Errorf("read index file for %s: %w", dep.AppCode, err)
+	}
+
+	indexKey := cacheIndexKey(dep.AppCode, dep.Version)
+	raw, err := cache.db.Get(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("read cache index for %s: %w", dep.AppCode, err)
+	}
+	if raw == nil {
+		if err := pacman.buildMetadataCacheForDependency(cache, dep.AppCode, idx); err != nil {
+			return nil, fmt.Errorf("build metadata cache for %s: %w", dep.AppCode, err)
+		}
+		if raw, err = cache.db.Get(indexKey); err != nil {
+			return nil, fmt.Errorf("read cache index for %s: %w", dep.AppCode, err)
+		}
+	}
+
+	var ids []string
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return nil, fmt.Errorf("decode cache index for %s: %w", dep.AppCode, err)
+	}
+
+	entities := make(map[string]json.RawMessage, len(ids))
+	for _, id := range ids {
+		data, err := cache.db.Get(cacheEntityKey(dep.AppCode, dep.Version, id))
+		if err != nil {
+			return nil, fmt.Errorf("read cached entity %s: %w", id, err)
+		}
+		entities[id] = data
+	}
+	return entities, nil
+}
+
+// writeEntitiesToTempFile round-trips entity JSON through a temporary metadata file so it
+// can be fed to the validator the same way parser.MetadataCacheFile is today. The caller is
+// responsible for removing the returned path.
+func writeEntitiesToTempFile(entities map[string]json.RawMessage) (string, error) {
+	data, err := json.Marshal(entities)
+	if err != nil {
+		return "", fmt.Errorf("marshal cached entities: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "cti-metadata-cache-*.json")
+	if err != nil {
+		return "", fmt.Errorf("create temp metadata file: %w", err)
+	}
+	defer tmp.Close()
+
+	if _, err := tmp.Write(data); err != nil {
+		return "", fmt.Errorf("write temp metadata file: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+// skipCachedDependencies drops entries from depends whose locked version is already present
+// in the metadata cache, making InstallIndexDependencies a no-op for them. Dependencies with
+// no existing lock entry (new installs) are always kept.
+func (pacman *PackageManager) skipCachedDependencies(depends []string) ([]string, error) {
+	if len(depends) == 0 || pacman.cacheMode != CacheModePogreb {
+		return depends, nil
+	}
+
+	cache, err := openMetadataCache(pacman.PackageCacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("open metadata cache: %w", err)
+	}
+	defer cache.Close()
+
+	var remaining []string
+	for _, dep := range depends {
+		sourceName, _ := ParseIndexDependency(dep)
+		pkgLock, ok := pacman.Package.IndexLock.Packages[sourceName]
+		if !ok {
+			remaining = append(remaining, dep)
+			continue
+		}
+		raw, err := cache.db.Get(cacheIndexKey(pkgLock.AppCode, pkgLock.Version))
+		if err != nil {
+			return nil, fmt.Errorf("read cache index for %s: %w", pkgLock.AppCode, err)
+		}
+		if raw == nil {
+			remaining = append(remaining, dep)
+		}
+	}
+	return remaining, nil
+}
+
+// PruneCache removes cache entries whose (appCode, version) no longer appears in the
+// IndexLock of the current package, reclaiming space held by stale or replaced dependencies.
+func (pacman *PackageManager) PruneCache() error {
+	cache, err := openMetadataCache(pacman.PackageCacheDir)
+	if err != nil {
+		return fmt.Errorf("open metadata cache: %w", err)
+	}
+	defer cache.Close()
+
+	known := make(map[string]struct{}, len(pacman.Package.IndexLock.Packages))
+	for _, dep := range pacman.Package.IndexLock.Packages {
+		known[dep.AppCode+"@"+dep.Version] = struct{}{}
+	}
+
+	var stale [][]byte
+	it := cache.db.Items()
+	for {
+		key, _, err := it.Next()
+		if err == pogreb.ErrIterationDone {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("iterate metadata cache: %w", err)
+		}
+
+		bundleKey, ok := cacheKeyBundle(key)
+		if !ok {
+			continue
+		}
+		if _, ok := known[bundleKey]; !ok {
+			stale = append(stale, append([]byte(nil), key...))
+		}
+	}
+
+	for _, key := range stale {
+		if err := cache.db.Delete(key); err != nil {
+			return fmt.Errorf("delete stale cache entry %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// cacheKeyBundle extracts the "<appCode>@<version>" prefix from a cache key of the form
+// "<appCode>@<version>/<cti-id or __index__>".
+func cacheKeyBundle(key []byte) (string, bool) {
+	s := string(key)
+	idx := strings.LastIndex(s, "/")
+	if idx == -1 {
+		return "", false
+	}
+	return s[:idx], true
+}
@@ -0,0 +1,330 @@
+package pacman
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// installOptions configures a concurrent install.
+type installOptions struct {
+	concurrency int
+}
+
+// InstallOption configures InstallNewDependenciesContext and InstallIndexDependenciesContext.
+type InstallOption func(*installOptions)
+
+// WithConcurrency sets how many dependencies are resolved, downloaded, and cache-built at
+// once. Defaults to runtime.GOMAXPROCS(0) when unset or non-positive.
+func WithConcurrency(n int) InstallOption {
+	return func(o *installOptions) {
+		o.concurrency = n
+	}
+}
+
+func (o installOptions) workers() int {
+	if o.concurrency > 0 {
+		return o.concurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// InstallNewDependenciesContext is InstallNewDependencies with a caller-supplied context and
+// a Concurrency option controlling the size of the download/cache-build worker pool.
+func (pacman *PackageManager) InstallNewDependenciesContext(ctx context.Context, depends []string, replace bool, opts ...InstallOption) ([]string, error) {
+	installed, replaced, err := pacman.installDependenciesContext(ctx, depends, replace, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install dependencies: %w", err)
+	}
+
+	pacman.recordDirectDependencies(ctx, depends, replaced)
+
+	if err = pacman.Package.SaveIndex(); err != nil {
+		return nil, fmt.Errorf("failed to save index: %w", err)
+	}
+	if err = pacman.Package.SaveIndexLock(); err != nil {
+		return nil, fmt.Errorf("failed to save index lock: %w", err)
+	}
+	return installed, nil
+}
+
+// InstallIndexDependenciesContext is InstallIndexDependencies with a caller-supplied context
+// and a Concurrency option controlling the size of the download/cache-build worker pool.
+func (pacman *PackageManager) InstallIndexDependenciesContext(ctx context.Context, opts ...InstallOption) ([]string, error) {
+	installed, _, err := pacman.installDependenciesContext(ctx, pacman.Package.Index.Depends, false, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install index dependencies: %w", err)
+	}
+	if err = pacman.Package.SaveIndexLock(); err != nil {
+		return nil, fmt.Errorf("failed to save index lock: %w", err)
+	}
+	return installed, nil
+}
+
+// recordDirectDependencies updates Index.Depends after installing depends: entries replaced
+// by a newer version are dropped, and any not already listed are added as direct deps.
+func (pacman *PackageManager) recordDirectDependencies(ctx context.Context, depends []string, replaced map[string]struct{}) {
+	if len(replaced) != 0 {
+		var kept []string
+		for _, idxDepName := range pacman.Package.Index.Depends {
+			depName, _ := ParseIndexDependency(idxDepName)
+			if _, ok := replaced[depName]; ok {
+				continue
+			}
+			kept = append(kept, idxDepName)
+		}
+		pacman.Package.Index.Depends = kept
+	}
+
+	for _, depName := range depends {
+		found := false
+		for _, idxDepName := range pacman.Package.Index.Depends {
+			if idxDepName == depName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pacman.Package.Index.Depends = append(pacman.Package.Index.Depends, depName)
+			loggerFromContext(ctx).Info(fmt.Sprintf("Added %s as direct dependency", depName))
+		}
+	}
+}
+
+// installDependenciesContext installs depends with concurrent resolution: each dependency that
+// is neither an OCI nor an HTTP registry reference is downloaded, verified, and cache-built by a
+// worker in a bounded pool, while a separate stage drains completed downloads off a channel to
+// rewrite dependency links, so link-rewriting for earlier dependencies overlaps with later
+// downloads. OCI and HTTP registry dependencies are then installed one at a time, outside the
+// pool, by installOCIDependencies and installHTTPDependencies.
+func (pacman *PackageManager) installDependenciesContext(ctx context.Context, depends []string, replace bool, opts ...InstallOption) ([]string, map[string]struct{}, error) {
+	var o installOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var ociDepends, httpDepends, otherDepends []string
+	for _, dep := range depends {
+		switch {
+		case isOCIRef(dep):
+			ociDepends = append(ociDepends, dep)
+		case isHTTPRef(dep):
+			httpDepends = append(httpDepends, dep)
+		default:
+			otherDepends = append(otherDepends, dep)
+		}
+	}
+
+	otherDepends, err := pacman.skipCachedDependencies(otherDepends)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check metadata cache: %w", err)
+	}
+
+	installed, replaced, err := pacman.downloadConcurrently(ctx, otherDepends, replace, o.workers())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download dependencies: %w", err)
+	}
+
+	ociInstalled, err := pacman.installOCIDependencies(ctx, ociDepends)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to install OCI dependencies: %w", err)
+	}
+	if err := pacman.verifyInstalled(ociInstalled); err != nil {
+		pacman.quarantine(ociInstalled)
+		return nil, nil, fmt.Errorf("failed to verify OCI dependencies: %w", err)
+	}
+	for _, sourceName := range ociInstalled {
+		pacman.report(ProgressEvent{Kind: CacheBuildStarted, SourceName: sourceName})
+		if err := pacman.buildDependencyCache(sourceName); err != nil {
+			pacman.report(ProgressEvent{Kind: CacheBuildFinished, SourceName: sourceName, Err: err})
+			return nil, nil, fmt.Errorf("failed to build cache for %s: %w", sourceName, err)
+		}
+		pacman.report(ProgressEvent{Kind: CacheBuildFinished, SourceName: sourceName})
+		if err := pacman.linkDependency(sourceName); err != nil {
+			return nil, nil, fmt.Errorf("failed to process installed dependencies: %w", err)
+		}
+	}
+	installed = append(installed, ociInstalled...)
+
+	httpInstalled, err := pacman.installHTTPDependencies(ctx, httpDepends)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to install registry dependencies: %w", err)
+	}
+	if err := pacman.verifyInstalled(httpInstalled); err != nil {
+		pacman.quarantine(httpInstalled)
+		return nil, nil, fmt.Errorf("failed to verify registry dependencies: %w", err)
+	}
+	for _, sourceName := range httpInstalled {
+		pacman.report(ProgressEvent{Kind: CacheBuildStarted, SourceName: sourceName})
+		if err := pacman.buildDependencyCache(sourceName); err != nil {
+			pacman.report(ProgressEvent{Kind: CacheBuildFinished, SourceName: sourceName, Err: err})
+			return nil, nil, fmt.Errorf("failed to build cache for %s: %w", sourceName, err)
+		}
+		pacman.report(ProgressEvent{Kind: CacheBuildFinished, SourceName: sourceName})
+		if err := pacman.linkDependency(sourceName); err != nil {
+			return nil, nil, fmt.Errorf("failed to process installed dependencies: %w", err)
+		}
+	}
+	installed = append(installed, httpInstalled...)
+
+	return installed, replaced, nil
+}
+
+// quarantine removes each installed source name's directory from DependenciesDir, used to fail
+// closed when verifyInstalled rejects a dependency that has already been extracted to disk.
+func (pacman *PackageManager) quarantine(installed []string) {
+	for _, sourceName := range installed {
+		pkgLock := pacman.Package.IndexLock.Packages[sourceName]
+		os.RemoveAll(filepath.Join(pacman.DependenciesDir, pkgLock.AppCode))
+	}
+}
+
+// downloadConcurrently fans out the download, signature verification, and cache build for
+// each dependency in depends across a worker pool of the given size, deduping diamond
+// dependencies via workerPool. Completed downloads are pushed onto a channel consumed by a
+// pipeline stage that rewrites dependency links as they arrive, so that stage overlaps with
+// downloads still in flight.
+func (pacman *PackageManager) downloadConcurrently(ctx context.Context, depends []string, replace bool, workers int) ([]string, map[string]struct{}, error) {
+	if len(depends) == 0 {
+		return nil, nil, nil
+	}
+
+	linked := make(chan string, len(depends))
+	var (
+		mu        sync.Mutex
+		installed []string
+		replaced  = make(map[string]struct{})
+	)
+
+	var linkErr error
+	linkDone := make(chan struct{})
+	go func() {
+		defer close(linkDone)
+		for sourceName := range linked {
+			if err := pacman.linkDependency(sourceName); err != nil && linkErr == nil {
+				linkErr = err
+			}
+		}
+	}()
+
+	err := workerPool(ctx, depends, workers,
+		func(dep string) string {
+			sourceName, _ := ParseIndexDependency(dep)
+			return sourceName
+		},
+		func(ctx context.Context, dep string) error {
+			sourceName, _ := ParseIndexDependency(dep)
+			pacman.report(ProgressEvent{Kind: DownloadStarted, SourceName: sourceName})
+			depInstalled, depReplaced, err := pacman.Download([]string{dep}, replace)
+			if err != nil {
+				pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: sourceName, Err: err})
+				return fmt.Errorf("download %s: %w", dep, err)
+			}
+			if err := pacman.verifyInstalled(depInstalled); err != nil {
+				pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: sourceName, Err: err})
+				// Download already extracted depInstalled onto disk; fail closed by
+				// removing it rather than leaving unverified content behind for
+				// buildDependencyCache/linkDependency to pick up.
+				pacman.quarantine(depInstalled)
+				return fmt.Errorf("verify %s: %w", dep, err)
+			}
+			pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: sourceName})
+
+			for _, name := range depInstalled {
+				pacman.report(ProgressEvent{Kind: CacheBuildStarted, SourceName: name})
+				if err := pacman.buildDependencyCache(name); err != nil {
+					pacman.report(ProgressEvent{Kind: CacheBuildFinished, SourceName: name, Err: err})
+					return err
+				}
+				pacman.report(ProgressEvent{Kind: CacheBuildFinished, SourceName: name})
+			}
+
+			mu.Lock()
+			installed = append(installed, depInstalled...)
+			for name := range depReplaced {
+				replaced[name] = struct{}{}
+			}
+			mu.Unlock()
+
+			for _, name := range depInstalled {
+				linked <- name
+			}
+			return nil
+		},
+	)
+
+	close(linked)
+	<-linkDone
+
+	if err != nil {
+		return nil, nil, err
+	}
+	if linkErr != nil {
+		return nil, nil, fmt.Errorf("failed to process installed dependencies: %w", linkErr)
+	}
+	return installed, replaced, nil
+}
+
+// workerPool runs fn for each item in items across a bounded pool of workers goroutines,
+// deduping items that map to the same dedupeKey via a sync.Once so diamond dependencies are
+// only processed once. The first error returned by fn cancels the shared context, which both
+// stops the dispatch loop from starting new items and is surfaced to in-flight calls to fn via
+// their ctx argument. Returns the first worker error, or the caller's ctx.Err() if the dispatch
+// loop broke out on its cancellation before every item was dispatched.
+func workerPool(ctx context.Context, items []string, workers int, dedupeKey func(string) string, fn func(ctx context.Context, item string) error) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, workers)
+
+	var (
+		onceMu sync.Mutex
+		once   = make(map[string]*sync.Once)
+	)
+
+dispatch:
+	for _, item := range items {
+		item := item
+		key := dedupeKey(item)
+
+		select {
+		case <-gctx.Done():
+			break dispatch
+		case sem <- struct{}{}:
+		}
+
+		onceMu.Lock()
+		dedupe, ok := once[key]
+		if !ok {
+			dedupe = &sync.Once{}
+			once[key] = dedupe
+		}
+		onceMu.Unlock()
+
+		g.Go(func() error {
+			defer func() { <-sem }()
+
+			var workErr error
+			dedupe.Do(func() {
+				workErr = fn(gctx, item)
+			})
+			return workErr
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	// g.Wait returning nil doesn't mean every item was dispatched: the dispatch loop above can
+	// break out on the caller's cancellation before some items ever got a g.Go call, in which
+	// case no worker returns an error and this is the only place that notices. The caller's ctx
+	// is checked rather than gctx, which errgroup always cancels once Wait returns.
+	return ctx.Err()
+}
@@ -0,0 +1,65 @@
+package pacman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkerPoolDedupesDiamondDependency(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+
+	// "shared" stands in for a dependency reached through two edges of a diamond, e.g.
+	// both app-a and app-b depending on app-shared@^1.0.0: it's dispatched three times but
+	// should only ever be worked on once.
+	items := []string{"shared", "shared", "shared", "unique"}
+	err := workerPool(context.Background(), items, 4,
+		func(item string) string { return item },
+		func(ctx context.Context, item string) error {
+			mu.Lock()
+			calls[item]++
+			mu.Unlock()
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("workerPool returned error: %v", err)
+	}
+	if calls["shared"] != 1 {
+		t.Errorf("calls[%q] = %d, want 1 (diamond dependency should be deduped)", "shared", calls["shared"])
+	}
+	if calls["unique"] != 1 {
+		t.Errorf("calls[%q] = %d, want 1", "unique", calls["unique"])
+	}
+}
+
+func TestWorkerPoolCancelsOnFirstError(t *testing.T) {
+	wantErr := errors.New("boom")
+
+	items := make([]string, 200)
+	for i := range items {
+		items[i] = fmt.Sprintf("item-%d", i)
+	}
+
+	var calls int32
+	err := workerPool(context.Background(), items, 1,
+		func(item string) string { return item },
+		func(ctx context.Context, item string) error {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return wantErr
+			}
+			return nil
+		},
+	)
+
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("workerPool error = %v, want %v", err, wantErr)
+	}
+	if got := atomic.LoadInt32(&calls); int(got) >= len(items) {
+		t.Errorf("workerPool dispatched all %d items despite the first one failing; want the first error to cancel the rest, got %d calls", len(items), got)
+	}
+}
@@ -0,0 +1,176 @@
+package pacman
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/acronis/go-cti/pkg/registry"
+)
+
+// isHTTPRef reports whether an Index.Depends entry names a sparse-registry dependency, e.g.
+// "https://registry.example.com/my-app@^1.2.0".
+func isHTTPRef(dep string) bool {
+	return strings.HasPrefix(dep, "http://") || strings.HasPrefix(dep, "https://")
+}
+
+// splitHTTPRef splits a "http(s)://host/<appCode>@<constraint>" reference into the registry's
+// base URL, the appCode, and the semver constraint.
+func splitHTTPRef(ref string) (baseURL, appCode, constraint string, err error) {
+	at := strings.LastIndex(ref, "@")
+	slash := strings.LastIndex(ref, "/")
+	if at == -1 || at < slash {
+		return "", "", "", fmt.Errorf("reference %q is missing an @<constraint>", ref)
+	}
+	return ref[:slash], ref[slash+1 : at], ref[at+1:], nil
+}
+
+// installHTTPDependencies resolves each "http(s)://host/<appCode>@<constraint>" entry against
+// its sparse registry, downloads the selected bundle.zip into DependencyDirName, extracts it in
+// place, and records the resolved archive URL and checksum in IndexLock so the install is
+// reproducible.
+func (pacman *PackageManager) installHTTPDependencies(ctx context.Context, depends []string) ([]string, error) {
+	var installed []string
+	for _, dep := range depends {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		baseURL, appCode, constraint, err := splitHTTPRef(dep)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse registry reference %s: %w", dep, err)
+		}
+
+		client := registry.NewClient(baseURL)
+		entry, err := client.Resolve(ctx, appCode, constraint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", dep, err)
+		}
+		pacman.report(ProgressEvent{Kind: DependencyResolved, SourceName: appCode, AppCode: appCode})
+
+		dir := filepath.Join(pacman.DependenciesDir, appCode)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create dependency directory for %s: %w", appCode, err)
+		}
+
+		pacman.report(ProgressEvent{Kind: DownloadStarted, SourceName: appCode, AppCode: appCode})
+		checksum, err := downloadHTTPArchive(ctx, client, entry, filepath.Join(dir, BundleName))
+		if err != nil {
+			pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: appCode, AppCode: appCode, Err: err})
+			return nil, fmt.Errorf("failed to download %s: %w", dep, err)
+		}
+		if checksum != entry.Checksum {
+			err := fmt.Errorf("checksum mismatch for %s: got %s, want %s", dep, checksum, entry.Checksum)
+			pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: appCode, AppCode: appCode, Err: err})
+			return nil, err
+		}
+		pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: appCode, AppCode: appCode})
+
+		if err := extractZipArchive(filepath.Join(dir, BundleName), dir); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", dep, err)
+		}
+
+		sourceName := appCode
+		pkgLock := pacman.Package.IndexLock.Packages[sourceName]
+		pkgLock.AppCode = appCode
+		pkgLock.Version = entry.Version
+		pkgLock.Source = baseURL + "/" + entry.Archive
+		pkgLock.Digest = entry.Checksum
+		pkgLock.Depends = entry.Depends
+		pacman.Package.IndexLock.Packages[sourceName] = pkgLock
+
+		installed = append(installed, sourceName)
+	}
+	return installed, nil
+}
+
+// downloadHTTPArchive streams entry's archive from the registry to dst, returning the SHA-256
+// of the bytes written so the caller can verify it against entry.Checksum.
+func downloadHTTPArchive(ctx context.Context, client *registry.Client, entry registry.VersionEntry, dst string) (string, error) {
+	rc, err := client.Download(ctx, entry)
+	if err != nil {
+		return "", fmt.Errorf("fetch archive: %w", err)
+	}
+	defer rc.Close()
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return "", fmt.Errorf("create %s: %w", dst, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), rc); err != nil {
+		return "", fmt.Errorf("write %s: %w", dst, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// extractZipArchive extracts every file in the zip archive at archivePath into dir, leaving
+// archivePath itself in place alongside the extracted files so a sibling bundle.sig can still
+// be verified against it, the same way verifyInstalled does for classic dependencies. Entries
+// whose name would resolve outside dir (zip-slip, e.g. "../../.ssh/authorized_keys") are rejected.
+func extractZipArchive(archivePath, dir string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", archivePath, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		dst, err := containedPath(dir, f.Name)
+		if err != nil {
+			return fmt.Errorf("bundle entry %s: %w", f.Name, err)
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+				return fmt.Errorf("create directory %s: %w", dst, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+			return fmt.Errorf("create directory for %s: %w", dst, err)
+		}
+		if err := extractZipFile(f, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containedPath joins dir and name and rejects the result if it would resolve outside dir,
+// guarding fetchLayer and extractZipArchive against path traversal via an attacker-controlled
+// archive entry name or OCI layer title.
+func containedPath(dir, name string) (string, error) {
+	dir = filepath.Clean(dir)
+	dst := filepath.Join(dir, name)
+	if dst != dir && !strings.HasPrefix(dst, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q escapes %q", name, dir)
+	}
+	return dst, nil
+}
+
+func extractZipFile(f *zip.File, dst string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("open %s in archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, rc); err != nil {
+		return fmt.Errorf("write %s: %w", dst, err)
+	}
+	return nil
+}
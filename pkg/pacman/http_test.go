@@ -0,0 +1,32 @@
+package pacman
+
+import "testing"
+
+func TestSplitHTTPRef(t *testing.T) {
+	baseURL, appCode, constraint, err := splitHTTPRef("https://registry.example.com/my-app@^1.2.0")
+	if err != nil {
+		t.Fatalf("splitHTTPRef returned error: %v", err)
+	}
+	if baseURL != "https://registry.example.com" || appCode != "my-app" || constraint != "^1.2.0" {
+		t.Errorf("splitHTTPRef = (%q, %q, %q), want (%q, %q, %q)",
+			baseURL, appCode, constraint, "https://registry.example.com", "my-app", "^1.2.0")
+	}
+}
+
+func TestSplitHTTPRefMissingConstraint(t *testing.T) {
+	if _, _, _, err := splitHTTPRef("https://registry.example.com/my-app"); err == nil {
+		t.Error("splitHTTPRef with no @<constraint>: expected error, got nil")
+	}
+}
+
+func TestIsHTTPRef(t *testing.T) {
+	if !isHTTPRef("https://registry.example.com/my-app@^1.2.0") {
+		t.Error("isHTTPRef: expected true for https:// reference")
+	}
+	if !isHTTPRef("http://registry.example.com/my-app@^1.2.0") {
+		t.Error("isHTTPRef: expected true for http:// reference")
+	}
+	if isHTTPRef("oci://registry.example.com/cti/my-bundle:1.0.0") {
+		t.Error("isHTTPRef: expected false for oci:// reference")
+	}
+}
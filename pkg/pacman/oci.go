@@ -0,0 +1,351 @@
+package pacman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/acronis/go-cti/pkg/cti"
+	_package "github.com/acronis/go-cti/pkg/package"
+	"github.com/acronis/go-cti/pkg/parser"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+const (
+	// MediaTypeIndex is the media type of the bundle's index.json, used as the OCI manifest config blob.
+	MediaTypeIndex = "application/vnd.cti.index.v1+json"
+	// MediaTypeMetadata is the media type of a serialized metadata layer.
+	MediaTypeMetadata = "application/vnd.cti.metadata.v1+json"
+	// MediaTypeAsset is the media type of a bundled CTI asset layer.
+	MediaTypeAsset = "application/vnd.cti.asset.v1+octet-stream"
+
+	// ArtifactType identifies a CTI bundle OCI artifact in its manifest.
+	ArtifactType = "application/vnd.cti.bundle.v1"
+)
+
+// PackFormat selects the archive format produced by PackageManager.Pack.
+type PackFormat int
+
+const (
+	// PackFormatZip is the default, backwards-compatible bundle.zip format.
+	PackFormatZip PackFormat = iota
+	// PackFormatOCI produces an in-memory OCI artifact that can be pushed with PushOCI.
+	PackFormatOCI
+)
+
+type packOptions struct {
+	format         PackFormat
+	signingKeyPath string
+}
+
+// PackOption configures PackageManager.Pack.
+type PackOption func(*packOptions)
+
+// WithOCIFormat selects the OCI artifact format instead of the default bundle.zip.
+func WithOCIFormat() PackOption {
+	return func(o *packOptions) {
+		o.format = PackFormatOCI
+	}
+}
+
+// ociArtifact holds the blobs produced by the most recent OCI-format Pack call, ready to be pushed.
+type ociArtifact struct {
+	store    *memory.Store
+	manifest ocispec.Descriptor
+}
+
+// packOCI mirrors the zip Pack walk over assets and serialized metadata, but pushes every
+// file into an in-memory content store as an OCI layer instead of a zip entry.
+func (pacman *PackageManager) packOCI(ctx context.Context, p *parser.Package) error {
+	store := memory.New()
+
+	idx := pacman.Package.Index.Clone()
+	idx.PutSerialized(parser.MetadataCacheFile)
+
+	configDesc, err := pushBlob(ctx, store, MediaTypeIndex, idx.ToBytes())
+	if err != nil {
+		return fmt.Errorf("failed to push index config blob: %w", err)
+	}
+
+	var layers []ocispec.Descriptor
+	for _, metadata := range idx.Serialized {
+		data, err := os.ReadFile(filepath.Join(p.BaseDir, metadata))
+		if err != nil {
+			return fmt.Errorf("failed to read serialized metadata %s: %w", metadata, err)
+		}
+		desc, err := pushBlob(ctx, store, MediaTypeMetadata, data)
+		if err != nil {
+			return fmt.Errorf("failed to push metadata layer %s: %w", metadata, err)
+		}
+		desc.Annotations = map[string]string{ocispec.AnnotationTitle: metadata}
+		layers = append(layers, desc)
+	}
+
+	for _, entity := range p.Registry.Instances {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		typ, ok := p.Registry.Types[cti.GetParentCti(entity.Cti)]
+		if !ok {
+			return fmt.Errorf("type %s not found", entity.Cti)
+		}
+		for key, annotation := range typ.Annotations {
+			if annotation.Asset == nil {
+				continue
+			}
+			assetPath := key.GetValue(entity.Values).String()
+			if assetPath == "" {
+				break
+			}
+			data, err := os.ReadFile(filepath.Join(p.BaseDir, assetPath))
+			if err != nil {
+				return fmt.Errorf("failed to read asset %s: %w", assetPath, err)
+			}
+			desc, err := pushBlob(ctx, store, MediaTypeAsset, data)
+			if err != nil {
+				return fmt.Errorf("failed to push asset layer %s: %w", assetPath, err)
+			}
+			desc.Annotations = map[string]string{ocispec.AnnotationTitle: assetPath}
+			layers = append(layers, desc)
+			pacman.report(ProgressEvent{Kind: AssetPacked, Path: assetPath})
+		}
+	}
+
+	manifestDesc, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, ArtifactType, oras.PackManifestOptions{
+		ConfigDescriptor: &configDesc,
+		Layers:           layers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pack OCI manifest: %w", err)
+	}
+
+	pacman.oci = &ociArtifact{store: store, manifest: manifestDesc}
+	return nil
+}
+
+func pushBlob(ctx context.Context, store content.Storage, mediaType string, data []byte) (ocispec.Descriptor, error) {
+	desc := content.NewDescriptorFromBytes(mediaType, data)
+	if err := store.Push(ctx, desc, bytes.NewReader(data)); err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("push blob %s: %w", desc.Digest, err)
+	}
+	return desc, nil
+}
+
+// PushOCI is PushOCIContext with context.Background().
+func (pacman *PackageManager) PushOCI(ref string) error {
+	return pacman.PushOCIContext(context.Background(), ref)
+}
+
+// PushOCIContext pushes the bundle produced by the most recent Pack(WithOCIFormat()) call to
+// ref, an OCI-compliant registry reference such as "registry.example.com/cti/my-bundle:1.0.0".
+func (pacman *PackageManager) PushOCIContext(ctx context.Context, ref string) error {
+	if pacman.oci == nil {
+		return fmt.Errorf("no OCI artifact available: call Pack(WithOCIFormat()) first")
+	}
+
+	repo, tag, err := splitOCIRef(ref)
+	if err != nil {
+		return fmt.Errorf("failed to parse OCI reference %s: %w", ref, err)
+	}
+
+	target, err := remote.NewRepository(repo)
+	if err != nil {
+		return fmt.Errorf("failed to create remote repository %s: %w", repo, err)
+	}
+
+	if err := pacman.oci.store.Tag(ctx, pacman.oci.manifest, tag); err != nil {
+		return fmt.Errorf("failed to tag bundle as %s: %w", tag, err)
+	}
+	if _, err := oras.Copy(ctx, pacman.oci.store, tag, target, tag, oras.DefaultCopyOptions); err != nil {
+		return fmt.Errorf("failed to push bundle to %s: %w", ref, err)
+	}
+	return nil
+}
+
+// pullOCI resolves ref against its registry and downloads the manifest and the layers
+// matching mediaTypes into dir, returning the resolved manifest digest for IndexLock.
+func pullOCI(ctx context.Context, ref string, dir string, mediaTypes map[string]bool) (string, error) {
+	repo, tag, err := splitOCIRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse OCI reference %s: %w", ref, err)
+	}
+
+	src, err := remote.NewRepository(repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to create remote repository %s: %w", repo, err)
+	}
+
+	dst := memory.New()
+	manifestDesc, err := oras.Copy(ctx, src, tag, dst, tag, oras.DefaultCopyOptions)
+	if err != nil {
+		return "", fmt.Errorf("failed to pull bundle %s: %w", ref, err)
+	}
+
+	manifest, err := fetchManifest(ctx, dst, manifestDesc)
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest %s: %w", ref, err)
+	}
+	if err := fetchConfig(ctx, dst, manifest.Config, dir); err != nil {
+		return "", fmt.Errorf("failed to fetch index config: %w", err)
+	}
+
+	successors, err := content.Successors(ctx, dst, manifestDesc)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve manifest successors: %w", err)
+	}
+	for _, desc := range successors {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if !mediaTypes[desc.MediaType] {
+			continue
+		}
+		if err := fetchLayer(ctx, dst, desc, dir); err != nil {
+			return "", err
+		}
+	}
+
+	return manifestDesc.Digest.String(), nil
+}
+
+// fetchManifest fetches and decodes the OCI manifest at desc, so its Config descriptor
+// (the bundle's index.json) can be resolved and fetched separately from its Layers.
+func fetchManifest(ctx context.Context, store content.Storage, desc ocispec.Descriptor) (ocispec.Manifest, error) {
+	rc, err := store.Fetch(ctx, desc)
+	if err != nil {
+		return ocispec.Manifest{}, fmt.Errorf("failed to fetch manifest %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return ocispec.Manifest{}, fmt.Errorf("failed to read manifest %s: %w", desc.Digest, err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ocispec.Manifest{}, fmt.Errorf("failed to decode manifest %s: %w", desc.Digest, err)
+	}
+	return manifest, nil
+}
+
+// fetchConfig fetches the manifest's config blob and writes it to dir/_package.IndexFileName,
+// so IndexLock-driven readers find the bundle's index.json on disk the same way they do for
+// classic and HTTP-registry installs.
+func fetchConfig(ctx context.Context, store content.Storage, desc ocispec.Descriptor, dir string) error {
+	rc, err := store.Fetch(ctx, desc)
+	if err != nil {
+		return fmt.Errorf("failed to fetch config %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	dst := filepath.Join(dir, _package.IndexFileName)
+	f, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, rc); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
+
+func fetchLayer(ctx context.Context, store content.Storage, desc ocispec.Descriptor, dir string) error {
+	rc, err := store.Fetch(ctx, desc)
+	if err != nil {
+		return fmt.Errorf("failed to fetch layer %s: %w", desc.Digest, err)
+	}
+	defer rc.Close()
+
+	title := desc.Annotations[ocispec.AnnotationTitle]
+	if title == "" {
+		title = desc.Digest.Encoded()
+	}
+	dst, err := containedPath(dir, title)
+	if err != nil {
+		return fmt.Errorf("layer %s: %w", desc.Digest, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", title, err)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, rc); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
+
+// isOCIRef reports whether an Index.Depends entry names an OCI artifact, e.g. "oci://registry/repo:tag".
+func isOCIRef(dep string) bool {
+	return strings.HasPrefix(dep, "oci://")
+}
+
+// ociLayerMediaTypes are the non-config layers InstallNewDependencies needs on disk; the config
+// blob (index.json) is always fetched separately by pullOCI via fetchConfig.
+var ociLayerMediaTypes = map[string]bool{
+	MediaTypeMetadata: true,
+	MediaTypeAsset:    true,
+}
+
+// installOCIDependencies resolves each "oci://registry/repo:tag" entry via the registry
+// client, downloading only the layers it needs into DependencyDirName, and records the
+// manifest digest in IndexLock so the install is reproducible.
+func (pacman *PackageManager) installOCIDependencies(ctx context.Context, depends []string) ([]string, error) {
+	var installed []string
+	for _, dep := range depends {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		sourceName, appCode := ParseIndexDependency(dep)
+		if appCode == "" {
+			appCode = sourceName
+		}
+		pacman.report(ProgressEvent{Kind: DependencyResolved, SourceName: sourceName, AppCode: appCode})
+
+		dir := filepath.Join(pacman.DependenciesDir, appCode)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create dependency directory for %s: %w", appCode, err)
+		}
+
+		pacman.report(ProgressEvent{Kind: DownloadStarted, SourceName: sourceName, AppCode: appCode})
+		digest, err := pullOCI(ctx, dep, dir, ociLayerMediaTypes)
+		if err != nil {
+			pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: sourceName, AppCode: appCode, Err: err})
+			return nil, fmt.Errorf("failed to pull OCI dependency %s: %w", dep, err)
+		}
+		pacman.report(ProgressEvent{Kind: DownloadFinished, SourceName: sourceName, AppCode: appCode})
+
+		pkgLock := pacman.Package.IndexLock.Packages[sourceName]
+		pkgLock.AppCode = appCode
+		pkgLock.Source = dep
+		pkgLock.Digest = digest
+		pacman.Package.IndexLock.Packages[sourceName] = pkgLock
+
+		installed = append(installed, sourceName)
+	}
+	return installed, nil
+}
+
+func splitOCIRef(ref string) (repo string, tag string, err error) {
+	ref = strings.TrimPrefix(ref, "oci://")
+	idx := strings.LastIndex(ref, ":")
+	slash := strings.LastIndex(ref, "/")
+	if idx == -1 || idx < slash {
+		return "", "", fmt.Errorf("reference %q is missing a tag", ref)
+	}
+	return ref[:idx], ref[idx+1:], nil
+}
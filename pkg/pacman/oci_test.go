@@ -0,0 +1,28 @@
+package pacman
+
+import "testing"
+
+func TestSplitOCIRef(t *testing.T) {
+	repo, tag, err := splitOCIRef("oci://registry.example.com/cti/my-bundle:1.0.0")
+	if err != nil {
+		t.Fatalf("splitOCIRef returned error: %v", err)
+	}
+	if repo != "registry.example.com/cti/my-bundle" || tag != "1.0.0" {
+		t.Errorf("splitOCIRef = (%q, %q), want (%q, %q)", repo, tag, "registry.example.com/cti/my-bundle", "1.0.0")
+	}
+}
+
+func TestSplitOCIRefMissingTag(t *testing.T) {
+	if _, _, err := splitOCIRef("oci://registry.example.com/cti/my-bundle"); err == nil {
+		t.Error("splitOCIRef with no tag: expected error, got nil")
+	}
+}
+
+func TestIsOCIRef(t *testing.T) {
+	if !isOCIRef("oci://registry.example.com/cti/my-bundle:1.0.0") {
+		t.Error("isOCIRef: expected true for oci:// reference")
+	}
+	if isOCIRef("https://registry.example.com/my-app@^1.0.0") {
+		t.Error("isOCIRef: expected false for http(s) reference")
+	}
+}
@@ -2,9 +2,9 @@ package pacman
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
-	"log/slog"
 	"os"
 	"path/filepath"
 
@@ -26,9 +26,22 @@ type PackageManager struct {
 	DependenciesDir string
 
 	BaseDir string
+
+	// oci holds the artifact produced by the most recent Pack(WithOCIFormat()) call.
+	oci *ociArtifact
+
+	// cacheMode selects how Validate loads dependency metadata; defaults to CacheModePogreb.
+	cacheMode CacheMode
+
+	// reporter receives structured progress events; defaults to noopReporter{}.
+	reporter ProgressReporter
+
+	// allowUnsigned lets verifyInstalled accept a dependency with no bundle.sig instead of
+	// failing closed; defaults to false.
+	allowUnsigned bool
 }
 
-func New(idxFile string) (*PackageManager, error) {
+func New(idxFile string, opts ...Option) (*PackageManager, error) {
 	pkg, err := _package.New(idxFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create package: %w", err)
@@ -38,111 +51,147 @@ func New(idxFile string) (*PackageManager, error) {
 		return nil, fmt.Errorf("failed to get package cache dir: %w", err)
 	}
 
-	return &PackageManager{
+	pacman := &PackageManager{
 		Package:         pkg,
 		PackageCacheDir: pkgCacheDir,
 		DependenciesDir: filepath.Join(pkg.BaseDir, DependencyDirName),
 		BaseDir:         pkg.BaseDir,
-	}, nil
-}
-
-func (pacman *PackageManager) InstallNewDependencies(depends []string, replace bool) ([]string, error) {
-	installed, replaced, err := pacman.installDependencies(depends, replace)
-	if err != nil {
-		return nil, fmt.Errorf("failed to install dependencies: %w", err)
-	}
-
-	// TODO: Possibly needs refactor
-	if len(replaced) != 0 {
-		var depends []string
-		for _, idxDepName := range pacman.Package.Index.Depends {
-			depName, _ := ParseIndexDependency(idxDepName)
-			if _, ok := replaced[depName]; ok {
-				continue
-			}
-			depends = append(depends, idxDepName)
-		}
-		pacman.Package.Index.Depends = depends
-	}
-
-	for _, depName := range depends {
-		found := false
-		for _, idxDepName := range pacman.Package.Index.Depends {
-			if idxDepName == depName {
-				found = true
-				break
-			}
-		}
-		if !found {
-			pacman.Package.Index.Depends = append(pacman.Package.Index.Depends, depName)
-			slog.Info(fmt.Sprintf("Added %s as direct dependency", depName))
-		}
+		cacheMode:       CacheModePogreb,
+		reporter:        noopReporter{},
 	}
 
-	if err = pacman.Package.SaveIndex(); err != nil {
-		return nil, fmt.Errorf("failed to save index: %w", err)
+	for _, opt := range opts {
+		opt(pacman)
 	}
 
-	if err = pacman.Package.SaveIndexLock(); err != nil {
-		return nil, fmt.Errorf("failed to save index lock: %w", err)
-	}
+	return pacman, nil
+}
 
-	return installed, nil
+// InstallNewDependencies is InstallNewDependenciesContext with context.Background().
+func (pacman *PackageManager) InstallNewDependencies(depends []string, replace bool) ([]string, error) {
+	return pacman.InstallNewDependenciesContext(context.Background(), depends, replace)
 }
 
+// InstallIndexDependencies is InstallIndexDependenciesContext with context.Background().
 func (pacman *PackageManager) InstallIndexDependencies() ([]string, error) {
-	installed, _, err := pacman.installDependencies(pacman.Package.Index.Depends, false)
-	if err != nil {
-		return nil, fmt.Errorf("failed to install index dependencies: %w", err)
-	}
-	if err = pacman.Package.SaveIndexLock(); err != nil {
-		return nil, fmt.Errorf("failed to save index lock: %w", err)
-	}
-	return installed, nil
+	return pacman.InstallIndexDependenciesContext(context.Background())
 }
 
-func (pacman *PackageManager) installDependencies(depends []string, replace bool) ([]string, map[string]struct{}, error) {
-	installed, replaced, err := pacman.Download(depends, replace)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to download dependencies: %w", err)
-	}
-	if err = pacman.processInstalledDependencies(installed); err != nil {
-		return nil, nil, fmt.Errorf("failed to process installed dependencies: %w", err)
-	}
-	return installed, replaced, nil
+// Validate is ValidateContext with context.Background().
+func (pacman *PackageManager) Validate() []error {
+	return pacman.ValidateContext(context.Background())
 }
 
-func (pacman *PackageManager) Validate() []error {
+// ValidateContext is Validate with a caller-supplied context, checked between dependencies so
+// a long validation run can be cancelled. Every error is also reported as a ValidationIssue
+// event before being appended to the returned slice.
+func (pacman *PackageManager) ValidateContext(ctx context.Context) []error {
+	issue := func(err error) []error {
+		pacman.report(ProgressEvent{Kind: ValidationIssue, Err: err})
+		return []error{err}
+	}
+
 	p, err := parser.ParsePackage(pacman.Package.Index.FilePath)
 	if err != nil {
-		return []error{fmt.Errorf("failed to parse package: %w", err)}
+		return issue(fmt.Errorf("failed to parse package: %w", err))
 	}
 	if err := p.DumpCache(); err != nil {
-		return []error{fmt.Errorf("failed to dump cache: %w", err)}
+		return issue(fmt.Errorf("failed to dump cache: %w", err))
 	}
 	validator := validator.MakeCtiValidator()
 	if err := validator.AddEntities(p.Registry.Total); err != nil {
-		return []error{fmt.Errorf("failed to add entities: %w", err)}
+		return issue(fmt.Errorf("failed to add entities: %w", err))
 	}
-	for _, dep := range pacman.Package.IndexLock.Packages {
-		idx, err := _package.ReadIndexFile(filepath.Join(pacman.DependenciesDir, dep.AppCode, _package.IndexFileName))
+
+	switch pacman.cacheMode {
+	case CacheModeNone:
+		for _, dep := range pacman.Package.IndexLock.Packages {
+			if err := ctx.Err(); err != nil {
+				return issue(err)
+			}
+			idx, err := _package.ReadIndexFile(filepath.Join(pacman.DependenciesDir, dep.AppCode, _package.IndexFileName))
+			if err != nil {
+				return issue(fmt.Errorf("failed to read index file for %s: %w", dep.AppCode, err))
+			}
+			depPkg, err := parser.ParsePackage(idx.FilePath)
+			if err != nil {
+				return issue(fmt.Errorf("failed to parse package %s: %w", dep.AppCode, err))
+			}
+			if err := validator.AddEntities(depPkg.Registry.Total); err != nil {
+				return issue(fmt.Errorf("failed to add entities for %s: %w", dep.AppCode, err))
+			}
+		}
+	case CacheModePogreb:
+		cache, err := openMetadataCache(pacman.PackageCacheDir)
 		if err != nil {
-			return []error{fmt.Errorf("failed to read index file for %s: %w", dep.AppCode, err)}
+			return issue(fmt.Errorf("failed to open metadata cache: %w", err))
 		}
-		// TODO: Automatically rebuild cache if missing?
-		if err := validator.AddFromFile(filepath.Join(idx.BaseDir, parser.MetadataCacheFile)); err != nil {
-			return []error{fmt.Errorf("failed to add entities from %s: %w", parser.MetadataCacheFile, err)}
+		defer cache.Close()
+
+		for _, dep := range pacman.Package.IndexLock.Packages {
+			if err := ctx.Err(); err != nil {
+				return issue(err)
+			}
+			entities, err := pacman.loadCachedEntities(cache, dep)
+			if err != nil {
+				return issue(fmt.Errorf("failed to load cached entities for %s: %w", dep.AppCode, err))
+			}
+			tmpFile, err := writeEntitiesToTempFile(entities)
+			if err != nil {
+				return issue(fmt.Errorf("failed to stage cached entities for %s: %w", dep.AppCode, err))
+			}
+			err = validator.AddFromFile(tmpFile)
+			os.Remove(tmpFile)
+			if err != nil {
+				return issue(fmt.Errorf("failed to add cached entities for %s: %w", dep.AppCode, err))
+			}
+		}
+	default: // CacheModeJSON
+		for _, dep := range pacman.Package.IndexLock.Packages {
+			if err := ctx.Err(); err != nil {
+				return issue(err)
+			}
+			idx, err := _package.ReadIndexFile(filepath.Join(pacman.DependenciesDir, dep.AppCode, _package.IndexFileName))
+			if err != nil {
+				return issue(fmt.Errorf("failed to read index file for %s: %w", dep.AppCode, err))
+			}
+			// TODO: Automatically rebuild cache if missing?
+			if err := validator.AddFromFile(filepath.Join(idx.BaseDir, parser.MetadataCacheFile)); err != nil {
+				return issue(fmt.Errorf("failed to add entities from %s: %w", parser.MetadataCacheFile, err))
+			}
 		}
 	}
+
 	// TODO: Validation for usage of indirect dependencies
-	return validator.ValidateAll()
+	errs := validator.ValidateAll()
+	for _, err := range errs {
+		pacman.report(ProgressEvent{Kind: ValidationIssue, Err: err})
+	}
+	return errs
+}
+
+// Pack is PackContext with context.Background().
+func (pacman *PackageManager) Pack(opts ...PackOption) error {
+	return pacman.PackContext(context.Background(), opts...)
 }
 
-func (pacman *PackageManager) Pack() error {
+// PackContext is Pack with a caller-supplied context, checked between assets and serialized
+// metadata files so a pack of a large bundle can be cancelled.
+func (pacman *PackageManager) PackContext(ctx context.Context, opts ...PackOption) error {
+	var o packOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	p, err := parser.ParsePackage(pacman.Package.Index.FilePath)
 	if err != nil {
 		return fmt.Errorf("failed to parse package: %w", err)
 	}
+
+	if o.format == PackFormatOCI {
+		return pacman.packOCI(ctx, p)
+	}
+
 	archive, err := os.Create(filepath.Join(pacman.BaseDir, BundleName))
 	if err != nil {
 		return fmt.Errorf("failed to create archive: %w", err)
@@ -150,9 +199,11 @@ func (pacman *PackageManager) Pack() error {
 	defer archive.Close()
 
 	zipWriter := zip.NewWriter(archive)
-	defer zipWriter.Close()
 
 	for _, entity := range p.Registry.Instances {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		typ, ok := p.Registry.Types[cti.GetParentCti(entity.Cti)]
 		if !ok {
 			return fmt.Errorf("type %s not found", entity.Cti)
@@ -178,7 +229,7 @@ func (pacman *PackageManager) Pack() error {
 				if err != nil {
 					return fmt.Errorf("failed to create asset %s in bundle: %w", assetPath, err)
 				}
-				if _, err = io.Copy(w, asset); err != nil {
+				if _, err = io.Copy(w, contextReader(ctx, asset)); err != nil {
 					return fmt.Errorf("failed to write asset %s to bundle: %w", assetPath, err)
 				}
 				return nil
@@ -186,6 +237,7 @@ func (pacman *PackageManager) Pack() error {
 			if err != nil {
 				return fmt.Errorf("failed to bundle asset %s: %w", assetPath, err)
 			}
+			pacman.report(ProgressEvent{Kind: AssetPacked, Path: assetPath})
 		}
 	}
 
@@ -202,6 +254,9 @@ func (pacman *PackageManager) Pack() error {
 	}
 
 	for _, metadata := range idx.Serialized {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		f, err := os.OpenFile(filepath.Join(p.BaseDir, metadata), os.O_RDONLY, 0o644)
 		if err != nil {
 			return fmt.Errorf("failed to open serialized metadata %s: %w", metadata, err)
@@ -212,29 +267,45 @@ func (pacman *PackageManager) Pack() error {
 		if err != nil {
 			return fmt.Errorf("failed to create serialized metadata %s in bundle: %w", metadata, err)
 		}
-		if _, err = io.Copy(w, f); err != nil {
+		if _, err = io.Copy(w, contextReader(ctx, f)); err != nil {
 			return fmt.Errorf("failed to write serialized metadata %s to bundle: %w", metadata, err)
 		}
 	}
 
+	if err := zipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+
+	if o.signingKeyPath != "" {
+		if err := pacman.signBundle(archive.Name(), o.signingKeyPath); err != nil {
+			return fmt.Errorf("failed to sign bundle: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (pacman *PackageManager) processInstalledDependencies(installed []string) error {
-	for _, sourceName := range installed {
-		pkgLock := pacman.Package.IndexLock.Packages[sourceName]
-		pkgPath := filepath.Join(pacman.DependenciesDir, pkgLock.AppCode)
-		for _, dep := range pkgLock.Depends {
-			depSourceName, _ := ParseIndexDependency(dep)
-			depPkgLock := pacman.Package.IndexLock.Packages[depSourceName]
-			err := pacman.rewriteDepLinks(pkgPath, depPkgLock.AppCode)
-			if err != nil {
-				return fmt.Errorf("failed to rewrite dependency links: %w", err)
-			}
-		}
-		if err := parser.BuildPackageCache(filepath.Join(pkgPath, _package.IndexFileName)); err != nil {
-			return fmt.Errorf("failed to build cache: %w", err)
+// linkDependency rewrites the dependency links of sourceName's own dependencies so that
+// nested bundles resolve against the shared DependenciesDir layout.
+func (pacman *PackageManager) linkDependency(sourceName string) error {
+	pkgLock := pacman.Package.IndexLock.Packages[sourceName]
+	pkgPath := filepath.Join(pacman.DependenciesDir, pkgLock.AppCode)
+	for _, dep := range pkgLock.Depends {
+		depSourceName, _ := ParseIndexDependency(dep)
+		depPkgLock := pacman.Package.IndexLock.Packages[depSourceName]
+		if err := pacman.rewriteDepLinks(pkgPath, depPkgLock.AppCode); err != nil {
+			return fmt.Errorf("failed to rewrite dependency links: %w", err)
 		}
 	}
 	return nil
 }
+
+// buildDependencyCache runs parser.BuildPackageCache for an already-downloaded dependency.
+func (pacman *PackageManager) buildDependencyCache(sourceName string) error {
+	pkgLock := pacman.Package.IndexLock.Packages[sourceName]
+	pkgPath := filepath.Join(pacman.DependenciesDir, pkgLock.AppCode)
+	if err := parser.BuildPackageCache(filepath.Join(pkgPath, _package.IndexFileName)); err != nil {
+		return fmt.Errorf("failed to build cache for %s: %w", sourceName, err)
+	}
+	return nil
+}
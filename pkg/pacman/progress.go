@@ -0,0 +1,117 @@
+package pacman
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// ProgressEventKind discriminates the payload carried by a ProgressEvent.
+type ProgressEventKind string
+
+const (
+	// DependencyResolved fires once a dependency reference has been resolved to a concrete
+	// version, before any bytes are fetched.
+	DependencyResolved ProgressEventKind = "dependency_resolved"
+	// DownloadStarted fires when a dependency's archive begins downloading.
+	DownloadStarted ProgressEventKind = "download_started"
+	// DownloadProgress carries BytesDone and BytesTotal for a dependency's archive download. No
+	// install path in this package emits it; downloads report only DownloadStarted and
+	// DownloadFinished.
+	DownloadProgress ProgressEventKind = "download_progress"
+	// DownloadFinished fires once a dependency's archive has been fully downloaded and
+	// verified.
+	DownloadFinished ProgressEventKind = "download_finished"
+	// CacheBuildStarted fires when parser.BuildPackageCache begins for a dependency.
+	CacheBuildStarted ProgressEventKind = "cache_build_started"
+	// CacheBuildFinished fires once a dependency's metadata cache has been built.
+	CacheBuildFinished ProgressEventKind = "cache_build_finished"
+	// AssetPacked fires once per CTI asset written into a bundle by Pack.
+	AssetPacked ProgressEventKind = "asset_packed"
+	// ValidationIssue fires for every error Validate encounters, in addition to it being
+	// returned from the call.
+	ValidationIssue ProgressEventKind = "validation_issue"
+)
+
+// ProgressEvent is emitted to a ProgressReporter by PackageManager operations. Only the fields
+// relevant to Kind are populated; the rest are zero.
+type ProgressEvent struct {
+	Kind ProgressEventKind
+
+	// SourceName is the Index.Depends entry's resolved key into IndexLock.Packages.
+	SourceName string
+	// AppCode is the dependency's CTI app code, when known.
+	AppCode string
+	// Path is a file path relevant to the event, e.g. the asset being packed.
+	Path string
+
+	// BytesDone and BytesTotal describe DownloadProgress; BytesTotal is 0 when unknown.
+	BytesDone  int64
+	BytesTotal int64
+
+	// Err is set on DownloadFinished (failure), CacheBuildFinished (failure), and
+	// ValidationIssue.
+	Err error
+}
+
+// ProgressReporter receives structured progress events from PackageManager operations. CLI,
+// CI, and IDE callers implement it to render install/pack/validate progress without scraping
+// slog output.
+type ProgressReporter interface {
+	Report(event ProgressEvent)
+}
+
+// noopReporter discards every event; it is the default when no ProgressReporter is configured.
+type noopReporter struct{}
+
+func (noopReporter) Report(ProgressEvent) {}
+
+// WithProgressReporter subscribes reporter to the structured progress events emitted by
+// install, pack, and validate operations.
+func WithProgressReporter(reporter ProgressReporter) Option {
+	return func(pacman *PackageManager) {
+		if reporter != nil {
+			pacman.reporter = reporter
+		}
+	}
+}
+
+func (pacman *PackageManager) report(event ProgressEvent) {
+	pacman.reporter.Report(event)
+}
+
+// loggerKey is the context key WithLogger stores a request-scoped *slog.Logger under.
+type loggerKey struct{}
+
+// WithLogger returns a context carrying logger, so that PackageManager operations invoked
+// with it log through a request-scoped slog handler instead of slog.Default().
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFromContext returns the logger attached to ctx by WithLogger, or slog.Default().
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ctxReader wraps an io.Reader with a context check on every Read, so a long io.Copy over it
+// stops promptly once ctx is cancelled instead of running to completion.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// contextReader wraps r so that io.Copy from it honors ctx's cancellation between reads.
+func contextReader(ctx context.Context, r io.Reader) io.Reader {
+	return ctxReader{ctx: ctx, r: r}
+}
+
+func (cr ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,321 @@
+package pacman
+
+import (
+	"archive/zip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/acronis/go-cti/pkg/filesys"
+)
+
+const (
+	// SignatureFileName is the name of the detached signature file written alongside BundleName.
+	SignatureFileName = "bundle.sig"
+
+	defaultKeyBits = 3072
+)
+
+// WithSigningKey signs the packed bundle with the RSA private key at keyPath, writing a
+// detached bundle.sig next to bundle.zip.
+func WithSigningKey(keyPath string) PackOption {
+	return func(o *packOptions) {
+		o.signingKeyPath = keyPath
+	}
+}
+
+// WithAllowUnsignedDependencies lets verifyInstalled accept a downloaded dependency that has no
+// bundle.sig, instead of the default fail-closed behavior of rejecting it. Opt in explicitly for
+// registries that don't sign their bundles yet; leave unset everywhere else.
+func WithAllowUnsignedDependencies() Option {
+	return func(pacman *PackageManager) {
+		pacman.allowUnsigned = true
+	}
+}
+
+// ManifestEntry describes one file inside a bundle archive, used to build the canonical
+// manifest that gets signed alongside the archive's own checksum.
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BundleSignature is the detached signature written alongside bundle.zip as bundle.sig.
+type BundleSignature struct {
+	KeyID        string          `json:"key_id"`
+	BundleSHA256 string          `json:"bundle_sha256"`
+	Manifest     []ManifestEntry `json:"manifest"`
+	Signature    []byte          `json:"signature"`
+}
+
+// signBundle computes the manifest and signature of the zip archive at archivePath and
+// writes them to a sibling bundle.sig file, using the RSA private key at keyPath.
+func (pacman *PackageManager) signBundle(archivePath, keyPath string) error {
+	key, err := loadPrivateKey(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load signing key %s: %w", keyPath, err)
+	}
+
+	manifest, err := buildBundleManifest(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to build bundle manifest: %w", err)
+	}
+
+	bundleDigest, err := sha256File(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to hash bundle: %w", err)
+	}
+
+	keyID, err := filesys.KeyID(&key.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to derive key id: %w", err)
+	}
+
+	sig := BundleSignature{
+		KeyID:        keyID,
+		BundleSHA256: bundleDigest,
+		Manifest:     manifest,
+	}
+
+	payload, err := json.Marshal(sig)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signature payload: %w", err)
+	}
+	digest := sha256.Sum256(payload)
+
+	signature, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest[:], nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign bundle: %w", err)
+	}
+	sig.Signature = signature
+
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal signature: %w", err)
+	}
+
+	sigPath := filepath.Join(filepath.Dir(archivePath), SignatureFileName)
+	if err := os.WriteFile(sigPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sigPath, err)
+	}
+	return nil
+}
+
+// verifyInstalled checks the detached signature of every freshly downloaded dependency against
+// the local keyring before its dependency links are rewritten or its metadata cache is built.
+// Fails closed: a dependency with no bundle.sig is rejected unless WithAllowUnsignedDependencies
+// was set on the PackageManager.
+func (pacman *PackageManager) verifyInstalled(installed []string) error {
+	if len(installed) == 0 {
+		return nil
+	}
+
+	keysDir, err := filesys.GetKeysDir()
+	if err != nil {
+		return fmt.Errorf("failed to get keys dir: %w", err)
+	}
+	keyring, err := filesys.LoadKeyring(keysDir)
+	if err != nil {
+		return fmt.Errorf("failed to load keyring: %w", err)
+	}
+
+	for _, sourceName := range installed {
+		pkgLock := pacman.Package.IndexLock.Packages[sourceName]
+		archivePath := filepath.Join(pacman.DependenciesDir, pkgLock.AppCode, BundleName)
+		sigPath := filepath.Join(filepath.Dir(archivePath), SignatureFileName)
+		if _, err := os.Stat(sigPath); os.IsNotExist(err) {
+			if pacman.allowUnsigned {
+				continue
+			}
+			return fmt.Errorf("dependency %s has no bundle.sig", pkgLock.AppCode)
+		}
+		if err := verifyBundleSignature(archivePath, keyring); err != nil {
+			return fmt.Errorf("failed to verify signature for %s: %w", pkgLock.AppCode, err)
+		}
+	}
+	return nil
+}
+
+// verifyBundleSignature verifies the bundle.sig next to archivePath against the trusted
+// keyring, failing closed when the signing key is unknown or the signature does not match.
+func verifyBundleSignature(archivePath string, keyring filesys.Keyring) error {
+	sigPath := filepath.Join(filepath.Dir(archivePath), SignatureFileName)
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", sigPath, err)
+	}
+
+	var sig BundleSignature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return fmt.Errorf("decode %s: %w", sigPath, err)
+	}
+
+	pub, ok := keyring[sig.KeyID]
+	if !ok {
+		return fmt.Errorf("unknown signing key %s", sig.KeyID)
+	}
+
+	bundleDigest, err := sha256File(archivePath)
+	if err != nil {
+		return fmt.Errorf("hash bundle: %w", err)
+	}
+	if bundleDigest != sig.BundleSHA256 {
+		return fmt.Errorf("bundle checksum does not match signature")
+	}
+
+	signature := sig.Signature
+	sig.Signature = nil
+	payload, err := json.Marshal(sig)
+	if err != nil {
+		return fmt.Errorf("marshal signature payload: %w", err)
+	}
+	digest := sha256.Sum256(payload)
+
+	if err := rsa.VerifyPSS(pub, crypto.SHA256, digest[:], signature, nil); err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	return nil
+}
+
+func buildBundleManifest(archivePath string) ([]ManifestEntry, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", archivePath, err)
+	}
+	defer r.Close()
+
+	var entries []ManifestEntry
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s in archive: %w", f.Name, err)
+		}
+		h := sha256.New()
+		size, err := io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("hash %s: %w", f.Name, err)
+		}
+		entries = append(entries, ManifestEntry{
+			Path:   f.Name,
+			Size:   size,
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+	}
+	return entries, nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("decode PEM block in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s: %w", path, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key %s is not an RSA private key", path)
+	}
+	return rsaKey, nil
+}
+
+// GenerateKeypair creates a new RSA keypair, writing the private key to keyPath and the
+// public key to keyPath+".pub", and returns the public key's key id.
+func GenerateKeypair(keyPath string) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, defaultKeyBits)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write private key %s: %w", keyPath, err)
+	}
+
+	pubDer, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	keyID, err := filesys.KeyID(&key.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive key id: %w", err)
+	}
+	pubPath := keyPath + ".pub"
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDer}), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write public key %s: %w", pubPath, err)
+	}
+
+	return keyID, nil
+}
+
+// InstallTrustedKey copies a public key PEM file into the local keyring (~/.cti/keys) so
+// that bundles signed with the matching private key verify on install.
+func InstallTrustedKey(pubKeyPath string) (string, error) {
+	data, err := os.ReadFile(pubKeyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", pubKeyPath, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("failed to decode PEM block in %s", pubKeyPath)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse public key %s: %w", pubKeyPath, err)
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("key %s is not an RSA public key", pubKeyPath)
+	}
+
+	keyID, err := filesys.KeyID(rsaPub)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive key id: %w", err)
+	}
+
+	keysDir, err := filesys.GetKeysDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get keys dir: %w", err)
+	}
+	dst := filepath.Join(keysDir, keyID+".pub")
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return keyID, nil
+}
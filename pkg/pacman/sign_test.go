@@ -0,0 +1,108 @@
+package pacman
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acronis/go-cti/pkg/filesys"
+)
+
+func writeTestArchive(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create %s: %v", path, err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	entry, err := w.Create("index.json")
+	if err != nil {
+		t.Fatalf("create zip entry: %v", err)
+	}
+	if _, err := entry.Write([]byte(`{"app_code":"test"}`)); err != nil {
+		t.Fatalf("write zip entry: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close zip writer: %v", err)
+	}
+}
+
+func TestSignAndVerifyBundleRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, BundleName)
+	writeTestArchive(t, archivePath)
+
+	keyPath := filepath.Join(dir, "signing.key")
+	if _, err := GenerateKeypair(keyPath); err != nil {
+		t.Fatalf("GenerateKeypair: %v", err)
+	}
+
+	pacman := &PackageManager{}
+	if err := pacman.signBundle(archivePath, keyPath); err != nil {
+		t.Fatalf("signBundle: %v", err)
+	}
+
+	pubData, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatalf("read public key: %v", err)
+	}
+	keysDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(keysDir, "trusted.pub"), pubData, 0o644); err != nil {
+		t.Fatalf("write trusted key: %v", err)
+	}
+	keyring, err := filesys.LoadKeyring(keysDir)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+
+	if err := verifyBundleSignature(archivePath, keyring); err != nil {
+		t.Fatalf("verifyBundleSignature: %v", err)
+	}
+}
+
+func TestVerifyBundleSignatureDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, BundleName)
+	writeTestArchive(t, archivePath)
+
+	keyPath := filepath.Join(dir, "signing.key")
+	if _, err := GenerateKeypair(keyPath); err != nil {
+		t.Fatalf("GenerateKeypair: %v", err)
+	}
+
+	pacman := &PackageManager{}
+	if err := pacman.signBundle(archivePath, keyPath); err != nil {
+		t.Fatalf("signBundle: %v", err)
+	}
+
+	pubData, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatalf("read public key: %v", err)
+	}
+	keysDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(keysDir, "trusted.pub"), pubData, 0o644); err != nil {
+		t.Fatalf("write trusted key: %v", err)
+	}
+	keyring, err := filesys.LoadKeyring(keysDir)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+
+	// Tamper with the archive after signing.
+	writeTestArchive(t, archivePath)
+	f, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open archive for tampering: %v", err)
+	}
+	if _, err := f.Write([]byte("tampered")); err != nil {
+		t.Fatalf("tamper with archive: %v", err)
+	}
+	f.Close()
+
+	if err := verifyBundleSignature(archivePath, keyring); err == nil {
+		t.Error("verifyBundleSignature: expected error for tampered archive, got nil")
+	}
+}
@@ -0,0 +1,102 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Client resolves dependency constraints against a sparse HTTP registry and downloads the
+// resolved archive. It is the client-side counterpart of Serve/Build.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the sparse index served at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Resolve fetches appCode's shard file and returns the highest published version satisfying
+// constraint.
+func (c *Client) Resolve(ctx context.Context, appCode, constraint string) (VersionEntry, error) {
+	entries, err := c.fetchShard(ctx, appCode)
+	if err != nil {
+		return VersionEntry{}, fmt.Errorf("fetch shard for %s: %w", appCode, err)
+	}
+
+	entry, ok := selectVersion(entries, constraint)
+	if !ok {
+		return VersionEntry{}, fmt.Errorf("no version of %s satisfies %q", appCode, constraint)
+	}
+	return entry, nil
+}
+
+// fetchShard downloads and parses appCode's shard file, one VersionEntry per line.
+func (c *Client) fetchShard(ctx context.Context, appCode string) ([]VersionEntry, error) {
+	url := c.BaseURL + "/" + ShardPath(appCode)
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var entries []VersionEntry
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry VersionEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("decode shard line: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read shard body: %w", err)
+	}
+	return entries, nil
+}
+
+// Download fetches entry's archive and returns its body; the caller is responsible for
+// closing it and verifying the bytes against entry.Checksum.
+func (c *Client) Download(ctx context.Context, entry VersionEntry) (io.ReadCloser, error) {
+	resp, err := c.get(ctx, c.BaseURL+"/"+strings.TrimLeft(entry.Archive, "/"))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request for %s: %w", url, err)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s for %s", resp.Status, url)
+	}
+	return resp, nil
+}
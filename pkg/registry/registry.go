@@ -0,0 +1,50 @@
+// Package registry implements a sparse, Cargo-style HTTP index for publishing and resolving
+// CTI bundles: metadata for each published appCode is served as its own small shard file
+// instead of one large index, so a client only ever fetches the shard for a dependency it
+// actually needs.
+package registry
+
+import "path/filepath"
+
+// ConfigFileName is the top-level file advertising the archive URL template and API version.
+const ConfigFileName = "config.json"
+
+// APIVersion is the sparse index layout version this package reads and writes.
+const APIVersion = 1
+
+// Config is served at <base>/config.json and tells clients how to build archive URLs and
+// which sparse index API version the server speaks.
+type Config struct {
+	// DownloadTemplate is the archive URL template, relative to the index base, with
+	// "{appCode}" and "{version}" placeholders, e.g. "{appCode}/{version}/bundle.zip".
+	DownloadTemplate string `json:"dl"`
+	APIVersion       int    `json:"api_version"`
+}
+
+// VersionEntry describes one published version of a bundle, as one line of its shard file.
+type VersionEntry struct {
+	AppCode  string   `json:"app_code"`
+	Version  string   `json:"version"`
+	Checksum string   `json:"checksum"`
+	Archive  string   `json:"archive"`
+	Depends  []string `json:"depends,omitempty"`
+}
+
+// ShardPath returns the path of appCode's shard file relative to the index root, following
+// Cargo's sparse index convention: names of 4 or more characters live under a two-level
+// directory keyed by their first two and next two characters ("ab/cd/abcd.json"); 1- and
+// 2-character names live in a directory named after their length ("1/a.json", "2/ab.json"),
+// and 3-character names in "3/<first character>/" ("3/a/abc.json").
+func ShardPath(appCode string) string {
+	name := appCode + ".json"
+	switch len(appCode) {
+	case 1:
+		return filepath.Join("1", name)
+	case 2:
+		return filepath.Join("2", name)
+	case 3:
+		return filepath.Join("3", appCode[:1], name)
+	default:
+		return filepath.Join(appCode[:2], appCode[2:4], name)
+	}
+}
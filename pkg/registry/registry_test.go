@@ -0,0 +1,21 @@
+package registry
+
+import "testing"
+
+func TestShardPath(t *testing.T) {
+	tests := []struct {
+		appCode string
+		want    string
+	}{
+		{"a", "1/a.json"},
+		{"ab", "2/ab.json"},
+		{"abc", "3/a/abc.json"},
+		{"abcd", "ab/cd/abcd.json"},
+		{"abcdef", "ab/cd/abcdef.json"},
+	}
+	for _, tt := range tests {
+		if got := ShardPath(tt.appCode); got != tt.want {
+			t.Errorf("ShardPath(%q) = %q, want %q", tt.appCode, got, tt.want)
+		}
+	}
+}
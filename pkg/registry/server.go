@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Serve (re)materializes the sparse index under dir from the published bundles it finds, then
+// serves dir over plain HTTP at addr. It blocks for the lifetime of the listener.
+func Serve(dir, addr string) error {
+	if err := Build(dir); err != nil {
+		return fmt.Errorf("build sparse index: %w", err)
+	}
+	return http.ListenAndServe(addr, http.FileServer(http.Dir(dir)))
+}
+
+// Build walks dir for bundles published in the "<dir>/<appCode>/<version>/bundle.zip" layout
+// produced by pacman.Pack, and (re)materializes the sparse index shard files plus
+// ConfigFileName under dir. Callers that republish bundles out-of-band can call Build directly
+// to refresh the index without restarting Serve.
+func Build(dir string) error {
+	versions, err := discoverVersions(dir)
+	if err != nil {
+		return fmt.Errorf("discover published bundles: %w", err)
+	}
+
+	for appCode, entries := range versions {
+		sort.Slice(entries, func(i, j int) bool {
+			return canonical(entries[i].Version) < canonical(entries[j].Version)
+		})
+		if err := writeShard(dir, appCode, entries); err != nil {
+			return fmt.Errorf("write shard for %s: %w", appCode, err)
+		}
+	}
+
+	cfg := Config{DownloadTemplate: "{appCode}/{version}/bundle.zip", APIVersion: APIVersion}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ConfigFileName), data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", ConfigFileName, err)
+	}
+	return nil
+}
+
+// discoverVersions walks dir for "bundle.zip" files and returns the published versions found,
+// grouped by appCode, reading each bundle's index.json for its Depends list.
+func discoverVersions(dir string) (map[string][]VersionEntry, error) {
+	versions := make(map[string][]VersionEntry)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "bundle.zip" {
+			return nil
+		}
+
+		versionDir := filepath.Dir(path)
+		appDir := filepath.Dir(versionDir)
+		appCode := filepath.Base(appDir)
+		version := filepath.Base(versionDir)
+
+		checksum, err := sha256File(path)
+		if err != nil {
+			return fmt.Errorf("checksum %s: %w", path, err)
+		}
+		depends, err := readBundleDepends(path)
+		if err != nil {
+			return fmt.Errorf("read depends from %s: %w", path, err)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("relative path for %s: %w", path, err)
+		}
+
+		versions[appCode] = append(versions[appCode], VersionEntry{
+			AppCode:  appCode,
+			Version:  version,
+			Checksum: checksum,
+			Archive:  filepath.ToSlash(rel),
+			Depends:  depends,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// readBundleDepends opens a packed bundle.zip and returns the Depends list from its
+// embedded index.json.
+func readBundleDepends(archivePath string) ([]string, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", archivePath, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.Name != "index.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open index.json in %s: %w", archivePath, err)
+		}
+		defer rc.Close()
+
+		var idx struct {
+			Depends []string `json:"depends"`
+		}
+		if err := json.NewDecoder(rc).Decode(&idx); err != nil {
+			return nil, fmt.Errorf("decode index.json in %s: %w", archivePath, err)
+		}
+		return idx.Depends, nil
+	}
+	return nil, fmt.Errorf("index.json not found in %s", archivePath)
+}
+
+// writeShard writes entries as newline-delimited JSON to appCode's shard path under dir,
+// mirroring Cargo's sparse index line format.
+func writeShard(dir, appCode string, entries []VersionEntry) error {
+	path := filepath.Join(dir, ShardPath(appCode))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("create shard directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("encode entry for %s@%s: %w", entry.AppCode, entry.Version, err)
+		}
+	}
+	return nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,51 @@
+package registry
+
+import (
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// selectVersion returns the highest published entry whose version satisfies constraint.
+func selectVersion(entries []VersionEntry, constraint string) (VersionEntry, bool) {
+	var best VersionEntry
+	found := false
+	for _, entry := range entries {
+		if !satisfies(entry.Version, constraint) {
+			continue
+		}
+		if !found || semver.Compare(canonical(entry.Version), canonical(best.Version)) > 0 {
+			best = entry
+			found = true
+		}
+	}
+	return best, found
+}
+
+// satisfies reports whether version meets constraint, which may be an exact version
+// ("1.2.3"), a caret range ("^1.2.0", same major, greater or equal), a minimum bound
+// (">=1.2.0"), or empty, which matches anything.
+func satisfies(version, constraint string) bool {
+	if constraint == "" {
+		return true
+	}
+	v := canonical(version)
+	switch {
+	case strings.HasPrefix(constraint, "^"):
+		c := canonical(strings.TrimPrefix(constraint, "^"))
+		return semver.Major(v) == semver.Major(c) && semver.Compare(v, c) >= 0
+	case strings.HasPrefix(constraint, ">="):
+		c := canonical(strings.TrimPrefix(constraint, ">="))
+		return semver.Compare(v, c) >= 0
+	default:
+		return canonical(constraint) == v
+	}
+}
+
+// canonical prefixes v with "v" if missing, as required by golang.org/x/mod/semver.
+func canonical(v string) string {
+	if strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
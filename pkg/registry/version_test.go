@@ -0,0 +1,50 @@
+package registry
+
+import "testing"
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.2.3", "", true},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.4", "1.2.3", false},
+		{"1.2.3", "^1.2.0", true},
+		{"1.9.0", "^1.2.0", true},
+		{"2.0.0", "^1.2.0", false},
+		{"1.1.0", "^1.2.0", false},
+		{"1.2.0", ">=1.2.0", true},
+		{"1.1.9", ">=1.2.0", false},
+		{"2.0.0", ">=1.2.0", true},
+	}
+	for _, tt := range tests {
+		if got := satisfies(tt.version, tt.constraint); got != tt.want {
+			t.Errorf("satisfies(%q, %q) = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestSelectVersion(t *testing.T) {
+	entries := []VersionEntry{
+		{Version: "1.0.0"},
+		{Version: "1.2.0"},
+		{Version: "1.9.0"},
+		{Version: "2.0.0"},
+	}
+
+	best, ok := selectVersion(entries, "^1.0.0")
+	if !ok || best.Version != "1.9.0" {
+		t.Errorf("selectVersion(^1.0.0) = %v, %v, want 1.9.0, true", best, ok)
+	}
+
+	best, ok = selectVersion(entries, "2.0.0")
+	if !ok || best.Version != "2.0.0" {
+		t.Errorf("selectVersion(2.0.0) = %v, %v, want 2.0.0, true", best, ok)
+	}
+
+	if _, ok := selectVersion(entries, "^3.0.0"); ok {
+		t.Errorf("selectVersion(^3.0.0) = ok, want no match")
+	}
+}